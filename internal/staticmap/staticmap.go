@@ -0,0 +1,283 @@
+// Package staticmap renders a static raster map, with markers and an
+// optional track, from a set of points.
+package staticmap
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const tileSize = 256
+
+// Point is a single location to mark on the rendered map.
+type Point struct {
+	Lat, Lng float64
+}
+
+// RenderConfig describes a map to render.
+type RenderConfig struct {
+	Points        []Point // markers, drawn as filled circles
+	Track         bool    // connect Points in order with a polyline
+	Width         int
+	Height        int
+	TileURL       string // template with {z}, {x}, {y} placeholders
+	CacheDir      string // tile cache directory; disabled if empty
+	Attribution   string
+	NoAttribution bool
+}
+
+// Render fetches the tiles covering the bounding box of cfg.Points at
+// an automatically chosen zoom level, stitches them into a single
+// image, overlays markers and an optional track, and returns the
+// result as a PNG.
+func Render(cfg RenderConfig) (io.ReadCloser, error) {
+	if len(cfg.Points) == 0 {
+		return nil, fmt.Errorf("staticmap: no points to render")
+	}
+	if cfg.Width == 0 {
+		cfg.Width = 800
+	}
+	if cfg.Height == 0 {
+		cfg.Height = 600
+	}
+	if cfg.TileURL == "" {
+		cfg.TileURL = "https://tile.openstreetmap.org/{z}/{x}/{y}.png"
+	}
+
+	zoom, centerX, centerY := fitZoom(cfg.Points, cfg.Width, cfg.Height)
+
+	img := image.NewRGBA(image.Rect(0, 0, cfg.Width, cfg.Height))
+	if err := drawTiles(img, cfg, zoom, centerX, centerY); err != nil {
+		return nil, err
+	}
+
+	if cfg.Track {
+		drawTrack(img, cfg.Points, zoom, centerX, centerY, cfg.Width, cfg.Height)
+	}
+	for _, p := range cfg.Points {
+		drawMarker(img, p, zoom, centerX, centerY, cfg.Width, cfg.Height)
+	}
+	if !cfg.NoAttribution {
+		attr := cfg.Attribution
+		if attr == "" {
+			attr = "© OpenStreetMap contributors"
+		}
+		drawAttribution(img, attr)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(&buf), nil
+}
+
+// lngLatToPixel projects lng/lat to global pixel coordinates at zoom,
+// using the standard Web Mercator formula.
+func lngLatToPixel(lat, lng float64, zoom int) (x, y float64) {
+	n := math.Exp2(float64(zoom)) * tileSize
+	x = (lng + 180) / 360 * n
+	latRad := lat * math.Pi / 180
+	y = (1 - math.Log(math.Tan(latRad)+1/math.Cos(latRad))/math.Pi) / 2 * n
+	return x, y
+}
+
+// fitZoom picks the highest zoom level at which the bounding box of
+// points still fits within width x height, and returns that zoom plus
+// the center point in global pixel coordinates at that zoom.
+func fitZoom(points []Point, width, height int) (zoom int, centerX, centerY float64) {
+	minLat, maxLat := points[0].Lat, points[0].Lat
+	minLng, maxLng := points[0].Lng, points[0].Lng
+	for _, p := range points[1:] {
+		minLat, maxLat = math.Min(minLat, p.Lat), math.Max(maxLat, p.Lat)
+		minLng, maxLng = math.Min(minLng, p.Lng), math.Max(maxLng, p.Lng)
+	}
+
+	for z := 18; z >= 0; z-- {
+		x0, y0 := lngLatToPixel(maxLat, minLng, z)
+		x1, y1 := lngLatToPixel(minLat, maxLng, z)
+		if x1-x0 <= float64(width) && y1-y0 <= float64(height) {
+			return z, (x0 + x1) / 2, (y0 + y1) / 2
+		}
+	}
+	return 0, 0, 0
+}
+
+func drawTiles(img *image.RGBA, cfg RenderConfig, zoom int, centerX, centerY float64) error {
+	originX := centerX - float64(cfg.Width)/2
+	originY := centerY - float64(cfg.Height)/2
+
+	firstTileX := int(math.Floor(originX / tileSize))
+	firstTileY := int(math.Floor(originY / tileSize))
+	lastTileX := int(math.Floor((originX + float64(cfg.Width)) / tileSize))
+	lastTileY := int(math.Floor((originY + float64(cfg.Height)) / tileSize))
+
+	for ty := firstTileY; ty <= lastTileY; ty++ {
+		for tx := firstTileX; tx <= lastTileX; tx++ {
+			tile, err := fetchTile(cfg.TileURL, cfg.CacheDir, zoom, tx, ty)
+			if err != nil {
+				return err
+			}
+			dstX := int(float64(tx*tileSize) - originX)
+			dstY := int(float64(ty*tileSize) - originY)
+			draw.Draw(img, image.Rect(dstX, dstY, dstX+tileSize, dstY+tileSize), tile, image.Point{}, draw.Src)
+		}
+	}
+	return nil
+}
+
+func fetchTile(tileURL, cacheDir string, z, x, y int) (image.Image, error) {
+	url := strings.NewReplacer(
+		"{z}", fmt.Sprint(z),
+		"{x}", fmt.Sprint(x),
+		"{y}", fmt.Sprint(y),
+	).Replace(tileURL)
+
+	if cacheDir != "" {
+		if img, err := readCachedTile(cacheDir, tileKey(z, x, y, tileURL)); err == nil {
+			return img, nil
+		}
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("fetch tile %d/%d/%d: %w", z, x, y, err)
+	}
+	defer resp.Body.Close()
+
+	bs, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("fetch tile %d/%d/%d: %w", z, x, y, err)
+	}
+
+	if cacheDir != "" {
+		_ = os.MkdirAll(cacheDir, 0755)
+		_ = os.WriteFile(filepath.Join(cacheDir, tileKey(z, x, y, tileURL)+".png"), bs, 0644)
+	}
+
+	img, err := png.Decode(bytes.NewReader(bs))
+	if err != nil {
+		return nil, fmt.Errorf("decode tile %d/%d/%d: %w", z, x, y, err)
+	}
+	return img, nil
+}
+
+func readCachedTile(cacheDir, key string) (image.Image, error) {
+	bs, err := os.ReadFile(filepath.Join(cacheDir, key+".png"))
+	if err != nil {
+		return nil, err
+	}
+	return png.Decode(bytes.NewReader(bs))
+}
+
+func tileKey(z, x, y int, tileURL string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d|%d|%d|%s", z, x, y, tileURL)))
+	return hex.EncodeToString(sum[:])
+}
+
+func drawMarker(img *image.RGBA, p Point, zoom int, centerX, centerY float64, width, height int) {
+	x, y := lngLatToPixel(p.Lat, p.Lng, zoom)
+	cx := int(x - centerX + float64(width)/2)
+	cy := int(y - centerY + float64(height)/2)
+
+	const radius = 5
+	red := color.RGBA{220, 40, 40, 255}
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			if dx*dx+dy*dy <= radius*radius {
+				img.Set(cx+dx, cy+dy, red)
+			}
+		}
+	}
+}
+
+func drawTrack(img *image.RGBA, points []Point, zoom int, centerX, centerY float64, width, height int) {
+	blue := color.RGBA{40, 80, 220, 255}
+	toPixel := func(p Point) (int, int) {
+		x, y := lngLatToPixel(p.Lat, p.Lng, zoom)
+		return int(x - centerX + float64(width)/2), int(y - centerY + float64(height)/2)
+	}
+
+	for i := 1; i < len(points); i++ {
+		x0, y0 := toPixel(points[i-1])
+		x1, y1 := toPixel(points[i])
+		drawLine(img, x0, y0, x1, y1, blue)
+	}
+}
+
+// drawLine draws a simple Bresenham line between two points.
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.Color) {
+	dx := abs(x1 - x0)
+	dy := -abs(y1 - y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx + dy
+
+	for {
+		img.Set(x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			break
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+// drawAttribution draws text over a translucent bar in the bottom-left
+// corner, using basicfont so no external font file is needed.
+func drawAttribution(img *image.RGBA, text string) {
+	bounds := img.Bounds()
+	const (
+		barHeight = 14
+		margin    = 4
+	)
+	bar := image.Rect(bounds.Min.X, bounds.Max.Y-barHeight, bounds.Max.X, bounds.Max.Y)
+	draw.Draw(img, bar, image.NewUniform(color.RGBA{0, 0, 0, 160}), image.Point{}, draw.Over)
+
+	face := basicfont.Face7x13
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(color.White),
+		Face: face,
+		Dot: fixed.Point26_6{
+			X: fixed.I(bounds.Min.X + margin),
+			Y: fixed.I(bounds.Max.Y - (barHeight-face.Height)/2 - 3),
+		},
+	}
+	d.DrawString(text)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,72 @@
+package staticmap
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestLngLatToPixelCenterAndZoomScaling(t *testing.T) {
+	// At zoom 0 the whole world is one 256x256 tile; (0,0) should land
+	// on the center pixel.
+	x, y := lngLatToPixel(0, 0, 0)
+	if math.Abs(x-128) > 0.5 || math.Abs(y-128) > 0.5 {
+		t.Fatalf("lngLatToPixel(0, 0, 0) = (%v, %v), want (~128, ~128)", x, y)
+	}
+
+	// Higher zoom should scale pixel coordinates by a power of two.
+	x0, y0 := lngLatToPixel(59.3, 18.0, 10)
+	x1, y1 := lngLatToPixel(59.3, 18.0, 11)
+	if math.Abs(x1-2*x0) > 1 || math.Abs(y1-2*y0) > 1 {
+		t.Fatalf("pixel coords did not double between zoom 10 and 11: (%v,%v) -> (%v,%v)", x0, y0, x1, y1)
+	}
+}
+
+func TestFitZoomFitsWithinBounds(t *testing.T) {
+	points := []Point{
+		{Lat: 59.3, Lng: 18.0},
+		{Lat: 59.35, Lng: 18.1},
+	}
+	width, height := 800, 600
+
+	zoom, centerX, centerY := fitZoom(points, width, height)
+	if zoom <= 0 {
+		t.Fatalf("fitZoom returned non-positive zoom %d for well-separated points", zoom)
+	}
+
+	for _, p := range points {
+		x, y := lngLatToPixel(p.Lat, p.Lng, zoom)
+		if math.Abs(x-centerX) > float64(width)/2+1 {
+			t.Errorf("point %v falls outside the rendered width at zoom %d", p, zoom)
+		}
+		if math.Abs(y-centerY) > float64(height)/2+1 {
+			t.Errorf("point %v falls outside the rendered height at zoom %d", p, zoom)
+		}
+	}
+
+	// One zoom level higher should no longer fit.
+	x0, y0 := lngLatToPixel(points[0].Lat, points[0].Lng, zoom+1)
+	x1, y1 := lngLatToPixel(points[1].Lat, points[1].Lng, zoom+1)
+	if math.Abs(x1-x0) <= float64(width) && math.Abs(y1-y0) <= float64(height) {
+		t.Errorf("zoom %d also fits the points; fitZoom should have picked it over %d", zoom+1, zoom)
+	}
+}
+
+func TestDrawAttributionPaintsText(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	drawAttribution(img, "test")
+
+	bounds := img.Bounds()
+	foundText := false
+	for y := bounds.Max.Y - 14; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if c := img.RGBAAt(x, y); c != (color.RGBA{0, 0, 0, 160}) {
+				foundText = true
+			}
+		}
+	}
+	if !foundText {
+		t.Fatal("drawAttribution did not paint any text pixels over the attribution bar")
+	}
+}
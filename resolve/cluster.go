@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// earthRadiusMeters is used for haversine distance calculations.
+const earthRadiusMeters = 6371000
+
+// cluster groups visits that are within clusterMeters of its first visit.
+type cluster struct {
+	lat, lng float64 // centroid, fixed at the first visit added
+	visits   []*visit
+}
+
+func newCluster(v *visit) *cluster {
+	return &cluster{lat: v.lat, lng: v.lng, visits: []*visit{v}}
+}
+
+func (c *cluster) add(v *visit) {
+	c.visits = append(c.visits, v)
+}
+
+func (c *cluster) MarshalJSON() ([]byte, error) {
+	first, last := c.visits[0], c.visits[0]
+	type visitProp struct {
+		Date    string `json:"date"`
+		Address string `json:"address"`
+	}
+	visits := make([]visitProp, len(c.visits))
+	for i, v := range c.visits {
+		if v.when.Before(first.when) {
+			first = v
+		}
+		if v.when.After(last.when) {
+			last = v
+		}
+		visits[i] = visitProp{Date: v.when.Format("2006-01-02"), Address: v.address}
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"type": "Feature",
+		"geometry": map[string]interface{}{
+			"type": "Point",
+			"coordinates": []float64{
+				float64(int(10000*c.lng)) / 10000,
+				float64(int(10000*c.lat)) / 10000,
+			},
+		},
+		"properties": map[string]interface{}{
+			"count":       len(c.visits),
+			"first_visit": first.when.Format("2006-01-02"),
+			"last_visit":  last.when.Format("2006-01-02"),
+			"visits":      visits,
+		},
+	})
+}
+
+// clusterVisits groups visits whose great-circle distance is within
+// clusterMeters into single clusters, using a grid-hash index keyed by
+// cellSize so the whole pass is O(n) rather than O(n^2).
+func clusterVisits(visits []*visit, clusterMeters float64) []*cluster {
+	cellSize := clusterMeters / 111000 // degrees per grid cell, roughly
+
+	grid := make(map[[2]int][]*cluster)
+	cellOf := func(lat, lng float64) [2]int {
+		return [2]int{int(math.Floor(lat / cellSize)), int(math.Floor(lng / cellSize))}
+	}
+
+	var clusters []*cluster
+	for _, v := range visits {
+		cell := cellOf(v.lat, v.lng)
+
+		var match *cluster
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				neighbor := [2]int{cell[0] + dy, cell[1] + dx}
+				for _, c := range grid[neighbor] {
+					if haversineMeters(v.lat, v.lng, c.lat, c.lng) <= clusterMeters {
+						match = c
+						break
+					}
+				}
+				if match != nil {
+					break
+				}
+			}
+			if match != nil {
+				break
+			}
+		}
+
+		if match != nil {
+			match.add(v)
+			continue
+		}
+
+		c := newCluster(v)
+		clusters = append(clusters, c)
+		grid[cell] = append(grid[cell], c)
+	}
+
+	return clusters
+}
+
+// haversineMeters returns the great-circle distance between two
+// lat/lng points, in meters.
+func haversineMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := rad(lat2 - lat1)
+	dLng := rad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(rad(lat1))*math.Cos(rad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+	return earthRadiusMeters * c
+}
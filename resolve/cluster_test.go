@@ -0,0 +1,102 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestHaversineMeters(t *testing.T) {
+	// Stockholm Central to Slussen is roughly 1.6km apart.
+	d := haversineMeters(59.3304, 18.0590, 59.3181, 18.0725)
+	if d < 1500 || d > 2500 {
+		t.Fatalf("haversineMeters = %v, want roughly 1600", d)
+	}
+
+	if d := haversineMeters(59.3, 18.0, 59.3, 18.0); d != 0 {
+		t.Fatalf("haversineMeters of identical points = %v, want 0", d)
+	}
+}
+
+func mustVisit(t *testing.T, date, address string, lat, lng float64) *visit {
+	t.Helper()
+	when, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &visit{address: address, when: when, lat: lat, lng: lng}
+}
+
+func TestClusterVisitsGroupsNearbyPoints(t *testing.T) {
+	visits := []*visit{
+		mustVisit(t, "2024-01-01", "Home", 59.3300, 18.0590),
+		mustVisit(t, "2024-01-02", "Home again", 59.3301, 18.0591), // a few meters from Home
+		mustVisit(t, "2024-01-03", "Far away", 59.4000, 18.2000),
+	}
+
+	clusters := clusterVisits(visits, 500)
+	if len(clusters) != 2 {
+		t.Fatalf("clusterVisits produced %d clusters, want 2", len(clusters))
+	}
+
+	var home *cluster
+	for _, c := range clusters {
+		if len(c.visits) == 2 {
+			home = c
+		}
+	}
+	if home == nil {
+		t.Fatal("expected one cluster with the two nearby visits merged")
+	}
+	if home.Label() != "Home (2 visits)" {
+		t.Errorf("Label() = %q, want %q", home.Label(), "Home (2 visits)")
+	}
+}
+
+func TestClusterCentroidIsFirstPointAdded(t *testing.T) {
+	// clusterVisits fixes a cluster's centroid at the first point
+	// added to it, rather than averaging every member's coordinates -
+	// this test pins that behavior so it isn't accidentally "improved"
+	// into an average without updating callers that rely on it.
+	visits := []*visit{
+		mustVisit(t, "2024-01-01", "A", 59.3300, 18.0590),
+		mustVisit(t, "2024-01-02", "B", 59.3301, 18.0591),
+	}
+
+	clusters := clusterVisits(visits, 500)
+	if len(clusters) != 1 {
+		t.Fatalf("clusterVisits produced %d clusters, want 1", len(clusters))
+	}
+
+	got := clusters[0]
+	if got.lat != visits[0].lat || got.lng != visits[0].lng {
+		t.Errorf("cluster centroid = (%v, %v), want first visit's coords (%v, %v)",
+			got.lat, got.lng, visits[0].lat, visits[0].lng)
+	}
+}
+
+func TestClusterWhenIsEarliestVisit(t *testing.T) {
+	visits := []*visit{
+		mustVisit(t, "2024-03-01", "A", 59.33, 18.059),
+		mustVisit(t, "2024-01-01", "B", 59.3301, 18.0591),
+		mustVisit(t, "2024-02-01", "C", 59.3302, 18.0592),
+	}
+
+	clusters := clusterVisits(visits, 500)
+	if len(clusters) != 1 {
+		t.Fatalf("clusterVisits produced %d clusters, want 1", len(clusters))
+	}
+
+	want, _ := time.Parse("2006-01-02", "2024-01-01")
+	if got := clusters[0].When(); !got.Equal(want) {
+		t.Errorf("When() = %v, want earliest visit %v", got, want)
+	}
+}
+
+func TestHaversineMetersSymmetric(t *testing.T) {
+	a := haversineMeters(59.3, 18.0, 59.35, 18.1)
+	b := haversineMeters(59.35, 18.1, 59.3, 18.0)
+	if math.Abs(a-b) > 0.001 {
+		t.Errorf("haversineMeters is not symmetric: %v vs %v", a, b)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// geoPoint is a single exportable feature: either a raw visit or a
+// cluster of nearby visits.
+type geoPoint interface {
+	Coords() (lat, lng float64)
+	Label() string
+	When() time.Time
+}
+
+func (v *visit) Coords() (float64, float64) { return v.lat, v.lng }
+func (v *visit) Label() string              { return v.address }
+func (v *visit) When() time.Time            { return v.when }
+
+func (c *cluster) Coords() (float64, float64) { return c.lat, c.lng }
+
+func (c *cluster) Label() string {
+	if len(c.visits) == 1 {
+		return c.visits[0].address
+	}
+	return fmt.Sprintf("%s (%d visits)", c.visits[0].address, len(c.visits))
+}
+
+func (c *cluster) When() time.Time {
+	first := c.visits[0].when
+	for _, v := range c.visits[1:] {
+		if v.when.Before(first) {
+			first = v.when
+		}
+	}
+	return first
+}
+
+// saveVisits writes visits (clustered unless noCluster is set) to
+// fname in format, one of "geojson", "gpx" or "kml". All three formats
+// share the same clustered/deduped point list.
+func saveVisits(visits []*visit, fname, format string, clusterMeters float64, noCluster, track bool) error {
+	var points []geoPoint
+	if noCluster {
+		points = make([]geoPoint, len(visits))
+		for i, v := range visits {
+			points[i] = v
+		}
+	} else {
+		for _, c := range clusterVisits(visits, clusterMeters) {
+			points = append(points, c)
+		}
+	}
+
+	switch format {
+	case "gpx":
+		return writeGPX(points, fname, track)
+	case "kml":
+		return writeKML(points, fname, track)
+	default:
+		return writeGeoJSON(points, fname)
+	}
+}
+
+func writeGeoJSON(points []geoPoint, fname string) error {
+	geojson := map[string]interface{}{
+		"type":     "FeatureCollection",
+		"features": points,
+	}
+	bs, err := json.MarshalIndent(geojson, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fname, bs, 0644)
+}
+
+type gpxFile struct {
+	XMLName   xml.Name      `xml:"gpx"`
+	Version   string        `xml:"version,attr"`
+	Creator   string        `xml:"creator,attr"`
+	Xmlns     string        `xml:"xmlns,attr"`
+	Waypoints []gpxWaypoint `xml:"wpt"`
+	Track     *gpxTrack     `xml:"trk,omitempty"`
+}
+
+type gpxWaypoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Time string  `xml:"time,omitempty"`
+	Name string  `xml:"name,omitempty"`
+}
+
+type gpxTrack struct {
+	Name string      `xml:"name,omitempty"`
+	Seg  gpxTrackSeg `xml:"trkseg"`
+}
+
+type gpxTrackSeg struct {
+	Points []gpxTrackPoint `xml:"trkpt"`
+}
+
+type gpxTrackPoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Time string  `xml:"time,omitempty"`
+}
+
+func writeGPX(points []geoPoint, fname string, track bool) error {
+	waypoints := make([]gpxWaypoint, len(points))
+	for i, p := range points {
+		lat, lng := p.Coords()
+		waypoints[i] = gpxWaypoint{Lat: lat, Lon: lng, Time: p.When().Format(time.RFC3339), Name: p.Label()}
+	}
+
+	g := gpxFile{
+		Version:   "1.1",
+		Creator:   "travel",
+		Xmlns:     "http://www.topografix.com/GPX/1/1",
+		Waypoints: waypoints,
+	}
+
+	if track {
+		g.Track = &gpxTrack{Name: "travel", Seg: gpxTrackSeg{Points: trackPoints(points)}}
+	}
+
+	return writeXML(g, fname)
+}
+
+func trackPoints(points []geoPoint) []gpxTrackPoint {
+	sorted := chronological(points)
+	trkpts := make([]gpxTrackPoint, len(sorted))
+	for i, p := range sorted {
+		lat, lng := p.Coords()
+		trkpts[i] = gpxTrackPoint{Lat: lat, Lon: lng, Time: p.When().Format(time.RFC3339)}
+	}
+	return trkpts
+}
+
+type kmlDocument struct {
+	XMLName  xml.Name    `xml:"kml"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	Document kmlInnerDoc `xml:"Document"`
+}
+
+type kmlInnerDoc struct {
+	Placemarks  []kmlPlacemark `xml:"Placemark"`
+	TrackFolder *kmlFolder     `xml:"Folder,omitempty"`
+}
+
+type kmlPlacemark struct {
+	Name      string        `xml:"name,omitempty"`
+	TimeStamp *kmlTimeStamp `xml:"TimeStamp,omitempty"`
+	Point     kmlPoint      `xml:"Point"`
+}
+
+type kmlTimeStamp struct {
+	When string `xml:"when"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlFolder struct {
+	Name      string            `xml:"name,omitempty"`
+	Placemark kmlTrackPlacemark `xml:"Placemark"`
+}
+
+type kmlTrackPlacemark struct {
+	Name       string        `xml:"name,omitempty"`
+	LineString kmlLineString `xml:"LineString"`
+}
+
+type kmlLineString struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+func writeKML(points []geoPoint, fname string, track bool) error {
+	placemarks := make([]kmlPlacemark, len(points))
+	for i, p := range points {
+		lat, lng := p.Coords()
+		placemarks[i] = kmlPlacemark{
+			Name:      p.Label(),
+			TimeStamp: &kmlTimeStamp{When: p.When().Format(time.RFC3339)},
+			Point:     kmlPoint{Coordinates: fmt.Sprintf("%g,%g,0", lng, lat)},
+		}
+	}
+
+	doc := kmlDocument{
+		Xmlns:    "http://www.opengis.net/kml/2.2",
+		Document: kmlInnerDoc{Placemarks: placemarks},
+	}
+
+	if track {
+		sorted := chronological(points)
+		coords := make([]string, len(sorted))
+		for i, p := range sorted {
+			lat, lng := p.Coords()
+			coords[i] = fmt.Sprintf("%g,%g,0", lng, lat)
+		}
+		doc.Document.TrackFolder = &kmlFolder{
+			Name: "Track",
+			Placemark: kmlTrackPlacemark{
+				Name:       "Track",
+				LineString: kmlLineString{Coordinates: strings.Join(coords, " ")},
+			},
+		}
+	}
+
+	return writeXML(doc, fname)
+}
+
+func chronological(points []geoPoint) []geoPoint {
+	sorted := slices.Clone(points)
+	slices.SortFunc(sorted, func(a, b geoPoint) int {
+		return a.When().Compare(b.When())
+	})
+	return sorted
+}
+
+func writeXML(v interface{}, fname string) error {
+	bs, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	bs = append([]byte(xml.Header), bs...)
+	return os.WriteFile(fname, bs, 0644)
+}
+
+func formatFromExt(fname string) string {
+	switch {
+	case strings.HasSuffix(fname, ".gpx"):
+		return "gpx"
+	case strings.HasSuffix(fname, ".kml"):
+		return "kml"
+	default:
+		return "geojson"
+	}
+}
+
+func extForFormat(format string) string {
+	switch format {
+	case "gpx":
+		return ".gpx"
+	case "kml":
+		return ".kml"
+	default:
+		return ".geojson"
+	}
+}
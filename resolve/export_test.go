@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFormatFromExtAndExtForFormat(t *testing.T) {
+	cases := map[string]string{
+		"out.gpx":     "gpx",
+		"out.kml":     "kml",
+		"out.geojson": "geojson",
+		"out":         "geojson",
+		"out.GPX":     "geojson", // extension matching is case-sensitive
+	}
+	for fname, want := range cases {
+		if got := formatFromExt(fname); got != want {
+			t.Errorf("formatFromExt(%q) = %q, want %q", fname, got, want)
+		}
+	}
+
+	for format, want := range map[string]string{"gpx": ".gpx", "kml": ".kml", "geojson": ".geojson", "": ".geojson"} {
+		if got := extForFormat(format); got != want {
+			t.Errorf("extForFormat(%q) = %q, want %q", format, got, want)
+		}
+	}
+}
+
+func testVisits(t *testing.T) []*visit {
+	t.Helper()
+	return []*visit{
+		mustVisit(t, "2024-01-02", "Work", 59.31, 18.01),
+		mustVisit(t, "2024-01-01", "Home", 59.30, 18.00),
+	}
+}
+
+func TestSaveVisitsGeoJSON(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "out.geojson")
+	if err := saveVisits(testVisits(t), fname, "geojson", 500, true, false); err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Properties struct {
+				Name string `json:"name"`
+				Date string `json:"date"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(bs, &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if doc.Type != "FeatureCollection" {
+		t.Errorf("type = %q, want FeatureCollection", doc.Type)
+	}
+	if len(doc.Features) != 2 {
+		t.Fatalf("got %d features, want 2 (noCluster=true)", len(doc.Features))
+	}
+}
+
+func TestSaveVisitsGPXWaypointsAndTrack(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "out.gpx")
+	if err := saveVisits(testVisits(t), fname, "gpx", 500, true, true); err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var g gpxFile
+	if err := xml.Unmarshal(bs, &g); err != nil {
+		t.Fatalf("output is not valid GPX XML: %v", err)
+	}
+	if len(g.Waypoints) != 2 {
+		t.Fatalf("got %d waypoints, want 2", len(g.Waypoints))
+	}
+	if g.Track == nil {
+		t.Fatal("track was requested but no <trk> was written")
+	}
+	if len(g.Track.Seg.Points) != 2 {
+		t.Fatalf("got %d track points, want 2", len(g.Track.Seg.Points))
+	}
+	// The track must be in chronological order, not CSV/input order.
+	if g.Track.Seg.Points[0].Lat != 59.30 {
+		t.Errorf("first track point lat = %v, want 59.30 (the earlier, 2024-01-01 visit)", g.Track.Seg.Points[0].Lat)
+	}
+	wantTime, _ := time.Parse("2006-01-02", "2024-01-01")
+	if g.Track.Seg.Points[0].Time != wantTime.Format(time.RFC3339) {
+		t.Errorf("first track point time = %q, want %q", g.Track.Seg.Points[0].Time, wantTime.Format(time.RFC3339))
+	}
+}
+
+func TestSaveVisitsGPXNoTrackByDefault(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "out.gpx")
+	if err := saveVisits(testVisits(t), fname, "gpx", 500, true, false); err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var g gpxFile
+	if err := xml.Unmarshal(bs, &g); err != nil {
+		t.Fatal(err)
+	}
+	if g.Track != nil {
+		t.Error("track was not requested but a <trk> was written anyway")
+	}
+}
+
+func TestSaveVisitsKMLPlacemarksAndTrack(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "out.kml")
+	if err := saveVisits(testVisits(t), fname, "kml", 500, true, true); err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc kmlDocument
+	if err := xml.Unmarshal(bs, &doc); err != nil {
+		t.Fatalf("output is not valid KML XML: %v", err)
+	}
+	if len(doc.Document.Placemarks) != 2 {
+		t.Fatalf("got %d placemarks, want 2", len(doc.Document.Placemarks))
+	}
+	for _, p := range doc.Document.Placemarks {
+		if p.TimeStamp == nil || p.TimeStamp.When == "" {
+			t.Errorf("placemark %q has no <TimeStamp><when>, needed for the time slider", p.Name)
+		}
+	}
+	if doc.Document.TrackFolder == nil {
+		t.Fatal("track was requested but no track Folder was written")
+	}
+	if doc.Document.TrackFolder.Placemark.LineString.Coordinates == "" {
+		t.Error("track Folder's LineString has no coordinates")
+	}
+}
+
+func TestSaveVisitsClustersUnlessNoCluster(t *testing.T) {
+	visits := []*visit{
+		mustVisit(t, "2024-01-01", "Home", 59.3300, 18.0590),
+		mustVisit(t, "2024-01-02", "Home again", 59.3301, 18.0591),
+	}
+
+	fname := filepath.Join(t.TempDir(), "clustered.geojson")
+	if err := saveVisits(visits, fname, "geojson", 500, false, false); err != nil {
+		t.Fatal(err)
+	}
+	bs, _ := os.ReadFile(fname)
+	var clustered struct {
+		Features []json.RawMessage `json:"features"`
+	}
+	if err := json.Unmarshal(bs, &clustered); err != nil {
+		t.Fatal(err)
+	}
+	if len(clustered.Features) != 1 {
+		t.Fatalf("clustered output has %d features, want 1 (nearby visits merged)", len(clustered.Features))
+	}
+
+	fname = filepath.Join(t.TempDir(), "unclustered.geojson")
+	if err := saveVisits(visits, fname, "geojson", 500, true, false); err != nil {
+		t.Fatal(err)
+	}
+	bs, _ = os.ReadFile(fname)
+	var unclustered struct {
+		Features []json.RawMessage `json:"features"`
+	}
+	if err := json.Unmarshal(bs, &unclustered); err != nil {
+		t.Fatal(err)
+	}
+	if len(unclustered.Features) != 2 {
+		t.Fatalf("-no-cluster output has %d features, want 2 (one per visit)", len(unclustered.Features))
+	}
+}
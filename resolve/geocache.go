@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// coord is a cached geocoding result.
+type coord struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// geoCache resolves addresses to coordinates, backed by a JSON file on
+// disk and a pluggable Geocoder for cache misses. Network calls are
+// serialized behind a ticker so repeated runs don't trip the upstream
+// geocoder's rate limits.
+type geoCache struct {
+	path     string
+	geocoder Geocoder
+	force    bool
+
+	mu      sync.Mutex
+	entries map[string]coord
+	dirty   bool
+
+	throttle <-chan time.Time
+}
+
+// newGeoCache loads path, if it exists, and returns a geoCache that
+// falls back to geocoder on misses. If force is true, cached entries
+// are ignored and every address is re-resolved.
+func newGeoCache(path string, geocoder Geocoder, force bool) *geoCache {
+	gc := &geoCache{
+		path:     path,
+		geocoder: geocoder,
+		force:    force,
+		entries:  make(map[string]coord),
+		throttle: time.Tick(200 * time.Millisecond),
+	}
+
+	if bs, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(bs, &gc.entries); err != nil {
+			log.Printf("geocache: discarding unreadable cache %s: %v", path, err)
+			gc.entries = make(map[string]coord)
+		}
+	}
+
+	return gc
+}
+
+// Decode returns the coordinates for address, using the cache when
+// possible and falling back to the configured Geocoder otherwise.
+func (c *geoCache) Decode(address string) (lat, lng float64, err error) {
+	key := normalizeAddress(address)
+
+	c.mu.Lock()
+	if !c.force {
+		if cc, ok := c.entries[key]; ok {
+			c.mu.Unlock()
+			return cc.Lat, cc.Lng, nil
+		}
+	}
+	c.mu.Unlock()
+
+	<-c.throttle
+
+	lat, lng, err = c.geocoder.Geocode(address)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = coord{Lat: lat, Lng: lng}
+	c.dirty = true
+	c.mu.Unlock()
+
+	return lat, lng, nil
+}
+
+// Save writes the cache to disk, atomically, if it has changed since
+// it was loaded.
+func (c *geoCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+
+	bs, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, bs, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return err
+	}
+
+	c.dirty = false
+	return nil
+}
+
+func normalizeAddress(address string) string {
+	return strings.ToLower(strings.Join(strings.Fields(address), " "))
+}
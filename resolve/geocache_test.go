@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeAddress(t *testing.T) {
+	cases := map[string]string{
+		"Main Street 1":        "main street 1",
+		"  Main   Street   1 ": "main street 1",
+		"MAIN STREET 1":        "main street 1",
+	}
+	for in, want := range cases {
+		if got := normalizeAddress(in); got != want {
+			t.Errorf("normalizeAddress(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGeoCacheDecodeCachesMisses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	g := &fakeGeocoder{lat: 59.3, lng: 18.0}
+	gc := newGeoCache(path, g, false)
+
+	lat, lng, err := gc.Decode("Stockholm, Sweden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lat != 59.3 || lng != 18.0 {
+		t.Fatalf("Decode = (%v, %v), want (59.3, 18.0)", lat, lng)
+	}
+	if g.calls != 1 {
+		t.Fatalf("geocoder called %d times on first Decode, want 1", g.calls)
+	}
+
+	if _, _, err := gc.Decode("stockholm,  sweden"); err != nil {
+		t.Fatal(err)
+	}
+	if g.calls != 1 {
+		t.Fatalf("geocoder called %d times on a repeat (normalized) address, want 1 (cache hit)", g.calls)
+	}
+}
+
+func TestGeoCacheDecodeUsesPreloadedEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	entries := map[string]coord{"stockholm, sweden": {Lat: 1, Lng: 2}}
+	bs, _ := json.Marshal(entries)
+	if err := os.WriteFile(path, bs, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := &fakeGeocoder{lat: 99, lng: 99}
+	gc := newGeoCache(path, g, false)
+
+	lat, lng, err := gc.Decode("Stockholm, Sweden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lat != 1 || lng != 2 {
+		t.Fatalf("Decode = (%v, %v), want the preloaded (1, 2)", lat, lng)
+	}
+	if g.calls != 0 {
+		t.Fatalf("geocoder called %d times for a cache hit, want 0", g.calls)
+	}
+}
+
+func TestGeoCacheForceIgnoresCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	entries := map[string]coord{"stockholm, sweden": {Lat: 1, Lng: 2}}
+	bs, _ := json.Marshal(entries)
+	if err := os.WriteFile(path, bs, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := &fakeGeocoder{lat: 59.3, lng: 18.0}
+	gc := newGeoCache(path, g, true)
+
+	lat, lng, err := gc.Decode("Stockholm, Sweden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lat != 59.3 || lng != 18.0 {
+		t.Fatalf("Decode with force=true = (%v, %v), want the geocoder's (59.3, 18.0)", lat, lng)
+	}
+	if g.calls != 1 {
+		t.Fatalf("geocoder called %d times with force=true, want 1", g.calls)
+	}
+}
+
+func TestGeoCacheSaveWritesAtomicallyAndReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	g := &fakeGeocoder{lat: 59.3, lng: 18.0}
+	gc := newGeoCache(path, g, false)
+
+	if _, _, err := gc.Decode("Stockholm, Sweden"); err != nil {
+		t.Fatal(err)
+	}
+	if err := gc.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("Save left a .tmp file behind: %v", err)
+	}
+
+	reloaded := newGeoCache(path, g, false)
+	if _, _, err := reloaded.Decode("Stockholm, Sweden"); err != nil {
+		t.Fatal(err)
+	}
+	if g.calls != 1 {
+		t.Fatalf("geocoder called %d times after reloading a saved cache, want 1 (no new call)", g.calls)
+	}
+}
+
+func TestGeoCacheSaveNoopWhenNotDirty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	gc := newGeoCache(path, &fakeGeocoder{}, false)
+
+	if err := gc.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Save created %s despite nothing having changed", path)
+	}
+}
+
+func TestGeoCacheDecodePropagatesGeocoderError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	wantErr := os.ErrPermission
+	gc := newGeoCache(path, &fakeGeocoder{err: wantErr}, false)
+
+	if _, _, err := gc.Decode("Nowhere"); err != wantErr {
+		t.Fatalf("Decode error = %v, want %v", err, wantErr)
+	}
+}
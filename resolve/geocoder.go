@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// Geocoder resolves a free-form address string to coordinates.
+type Geocoder interface {
+	Geocode(address string) (lat, lng float64, err error)
+}
+
+// googleGeocoder uses the Google Maps Geocoding API.
+type googleGeocoder struct {
+	apiKey string
+}
+
+func newGoogleGeocoder(apiKey string) *googleGeocoder {
+	if apiKey == "" {
+		apiKey = os.Getenv("GOOGLE_MAPS_API_KEY")
+	}
+	return &googleGeocoder{apiKey: apiKey}
+}
+
+func (g *googleGeocoder) Geocode(address string) (float64, float64, error) {
+	if g.apiKey == "" {
+		return 0, 0, fmt.Errorf("geocode %q: no Google Maps API key configured", address)
+	}
+
+	u := "https://maps.googleapis.com/maps/api/geocode/json?" + url.Values{
+		"address": {address},
+		"key":     {g.apiKey},
+	}.Encode()
+
+	resp, err := http.Get(u)
+	if err != nil {
+		return 0, 0, fmt.Errorf("geocode %q: %w", address, err)
+	}
+	defer resp.Body.Close()
+
+	var res result
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return 0, 0, fmt.Errorf("geocode %q: %w", address, err)
+	}
+	if len(res.Results) == 0 {
+		return 0, 0, fmt.Errorf("geocode %q: no results", address)
+	}
+
+	loc := res.Results[0].Geometry.Location
+	return loc.Lat, loc.Lng, nil
+}
+
+// nominatimGeocoder uses the OpenStreetMap Nominatim search API, which
+// requires no API key but asks for a descriptive User-Agent.
+type nominatimGeocoder struct {
+	userAgent string
+}
+
+func newNominatimGeocoder() *nominatimGeocoder {
+	return &nominatimGeocoder{userAgent: "travel-resolve/1.0"}
+}
+
+func (g *nominatimGeocoder) Geocode(address string) (float64, float64, error) {
+	u := "https://nominatim.openstreetmap.org/search?" + url.Values{
+		"q":      {address},
+		"format": {"json"},
+		"limit":  {"1"},
+	}.Encode()
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("geocode %q: %w", address, err)
+	}
+	req.Header.Set("User-Agent", g.userAgent)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, 0, fmt.Errorf("geocode %q: %w", address, err)
+	}
+	defer resp.Body.Close()
+
+	var res []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return 0, 0, fmt.Errorf("geocode %q: %w", address, err)
+	}
+	if len(res) == 0 {
+		return 0, 0, fmt.Errorf("geocode %q: no results", address)
+	}
+
+	var lat, lng float64
+	fmt.Sscanf(res[0].Lat, "%g", &lat)
+	fmt.Sscanf(res[0].Lon, "%g", &lng)
+	return lat, lng, nil
+}
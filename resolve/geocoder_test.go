@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+)
+
+// fakeGeocoder is a Geocoder stub for tests that should never make a
+// real network call.
+type fakeGeocoder struct {
+	calls int
+	lat   float64
+	lng   float64
+	err   error
+}
+
+func (g *fakeGeocoder) Geocode(address string) (float64, float64, error) {
+	g.calls++
+	if g.err != nil {
+		return 0, 0, g.err
+	}
+	return g.lat, g.lng, nil
+}
+
+func TestGoogleGeocoderRequiresAPIKey(t *testing.T) {
+	t.Setenv("GOOGLE_MAPS_API_KEY", "")
+
+	g := newGoogleGeocoder("")
+	if _, _, err := g.Geocode("Stockholm, Sweden"); err == nil {
+		t.Fatal("Geocode with no API key configured should fail without making a request")
+	}
+}
+
+func TestNewGoogleGeocoderFallsBackToEnvVar(t *testing.T) {
+	t.Setenv("GOOGLE_MAPS_API_KEY", "env-key")
+
+	g := newGoogleGeocoder("")
+	if g.apiKey != "env-key" {
+		t.Errorf("apiKey = %q, want %q from $GOOGLE_MAPS_API_KEY", g.apiKey, "env-key")
+	}
+
+	g = newGoogleGeocoder("flag-key")
+	if g.apiKey != "flag-key" {
+		t.Errorf("apiKey = %q, want the explicitly passed %q", g.apiKey, "flag-key")
+	}
+}
+
+func TestNewNominatimGeocoderSetsUserAgent(t *testing.T) {
+	g := newNominatimGeocoder()
+	if g.userAgent == "" {
+		t.Error("nominatimGeocoder must set a User-Agent; the API requires one")
+	}
+}
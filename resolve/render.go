@@ -0,0 +1,39 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/calmh/travel/internal/staticmap"
+)
+
+// renderPNG rasterizes visits as a static map and writes it to fname.
+func renderPNG(visits []*visit, fname string, width, height int, tileURL, tileCache string, noAttribution bool) error {
+	points := make([]staticmap.Point, len(visits))
+	for i, v := range visits {
+		points[i] = staticmap.Point{Lat: v.lat, Lng: v.lng}
+	}
+
+	rc, err := staticmap.Render(staticmap.RenderConfig{
+		Points:        points,
+		Track:         true,
+		Width:         width,
+		Height:        height,
+		TileURL:       tileURL,
+		CacheDir:      tileCache,
+		NoAttribution: noAttribution,
+	})
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	fd, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	_, err = io.Copy(fd, rc)
+	return err
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"flag"
-	"fmt"
 	"log"
 	"os"
 	"slices"
@@ -31,7 +30,7 @@ type visit struct {
 	lat, lng float64
 }
 
-func visitFromStrings(fs []string) *visit {
+func visitFromStrings(fs []string, gc *geoCache) *visit {
 	if len(fs) != 5 {
 		return nil
 	}
@@ -43,6 +42,15 @@ func visitFromStrings(fs []string) *visit {
 	v.lat, _ = strconv.ParseFloat(strings.TrimSpace(fs[3]), 64)
 	v.lng, _ = strconv.ParseFloat(strings.TrimSpace(fs[4]), 64)
 
+	if v.lat == 0 && v.lng == 0 && v.address != "" && gc != nil {
+		lat, lng, err := gc.Decode(v.address)
+		if err != nil {
+			log.Printf("geocode %q: %v", v.address, err)
+		} else {
+			v.lat, v.lng = lat, lng
+		}
+	}
+
 	return &v
 }
 
@@ -64,51 +72,96 @@ func (v *visit) MarshalJSON() ([]byte, error) {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serveMain(os.Args[2:])
+		return
+	}
+
 	file := flag.String("file", "travel.csv", "CSV file name")
+	apiKey := flag.String("apikey", "", "Google Maps API key (or $GOOGLE_MAPS_API_KEY)")
+	cacheFile := flag.String("cache", "travel.geocache.json", "geocode cache file name")
+	nominatim := flag.Bool("nominatim", false, "use Nominatim/OpenStreetMap instead of Google for geocoding")
+	forceGeocode := flag.Bool("geocode", false, "ignore the geocode cache and re-resolve every address")
+	pngOut := flag.String("png", "", "render a static map PNG to this file")
+	pngWidth := flag.Int("width", 800, "static map width in pixels")
+	pngHeight := flag.Int("height", 600, "static map height in pixels")
+	tileURL := flag.String("tile-url", "https://tile.openstreetmap.org/{z}/{x}/{y}.png", "tile URL template for the static map")
+	tileCache := flag.String("tile-cache", "travel.tilecache", "tile cache directory for the static map")
+	noAttribution := flag.Bool("no-attribution", false, "omit the attribution overlay on the static map")
+	clusterMeters := flag.Float64("cluster-meters", 500, "cluster visits within this many meters of each other into one feature")
+	noCluster := flag.Bool("no-cluster", false, "emit one feature per visit instead of clustering")
+	out := flag.String("out", "", "output file (default: <file> with the format's extension)")
+	format := flag.String("format", "", "output format: geojson, gpx or kml (default: inferred from -out, else geojson)")
+	track := flag.Bool("track", false, "include a track connecting visits chronologically (gpx/kml)")
 	flag.Parse()
 
-	fd, err := os.Open(*file)
+	var geocoder Geocoder = newGoogleGeocoder(*apiKey)
+	if *nominatim {
+		geocoder = newNominatimGeocoder()
+	}
+	gc := newGeoCache(*cacheFile, geocoder, *forceGeocode)
+
+	visits, err := loadVisits(*file, gc)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	if err := gc.Save(); err != nil {
+		log.Printf("saving geocode cache: %v", err)
+	}
+
+	outFormat := strings.ToLower(*format)
+	if outFormat == "" {
+		if *out != "" {
+			outFormat = formatFromExt(*out)
+		} else {
+			outFormat = "geojson"
+		}
+	}
+	switch outFormat {
+	case "geojson", "gpx", "kml":
+	default:
+		log.Fatalf("unknown -format %q: must be geojson, gpx or kml", *format)
+	}
+	fname := *out
+	if fname == "" {
+		fname = strings.Replace(*file, ".csv", extForFormat(outFormat), 1)
+	}
+
+	if err := saveVisits(visits, fname, outFormat, *clusterMeters, *noCluster, *track); err != nil {
+		log.Fatal(err)
+	}
+
+	if *pngOut != "" {
+		if err := renderPNG(visits, *pngOut, *pngWidth, *pngHeight, *tileURL, *tileCache, *noAttribution); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// loadVisits reads file as CSV, geocoding rows with missing
+// coordinates, and returns the visits sorted chronologically.
+// Deduplication happens downstream, via clusterVisits.
+func loadVisits(file string, gc *geoCache) ([]*visit, error) {
+	fd, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
 	r := csv.NewReader(fd)
 	var visits []*visit
-	seenCoords := make(map[string]struct{})
 	for {
 		in, err := r.Read()
 		if err != nil {
 			break
 		}
-		visit := visitFromStrings(in)
-		coords := fmt.Sprintf("%.04f,%.04f", visit.lat, visit.lat)
-		if _, ok := seenCoords[coords]; ok {
-			continue
-		}
-		seenCoords[coords] = struct{}{}
-		visits = append(visits, visit)
+		visits = append(visits, visitFromStrings(in, gc))
 	}
-	fd.Close()
 
 	slices.SortFunc(visits, func(a, b *visit) int {
 		return a.when.Compare(b.when)
 	})
 
-	fname := strings.Replace(*file, ".csv", ".geojson", 1)
-	saveVisits(visits, fname)
-}
-
-func saveVisits(visits []*visit, fname string) {
-	geojson := map[string]interface{}{
-		"type":     "FeatureCollection",
-		"features": visits,
-	}
-	bs, _ := json.MarshalIndent(geojson, "", "  ")
-
-	fd, err := os.Create(fname)
-	if err != nil {
-		log.Fatal(err)
-	}
-	fd.Write(bs)
-	fd.Close()
+	return visits, nil
 }
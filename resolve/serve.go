@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// location is the shape the Grafana Worldmap panel expects from
+// GET /locations.
+type location struct {
+	Key       string  `json:"key"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Name      string  `json:"name"`
+}
+
+// metric is the shape the Grafana Worldmap panel expects from
+// GET /metrics.
+type metric struct {
+	Key   string `json:"key"`
+	Value int    `json:"value"`
+}
+
+// visitStore holds the current visit set loaded from a CSV file and
+// reloads it whenever the file changes on disk. It clusters visits the
+// same way the file writer does, so GET /features.geojson matches the
+// FeatureCollection saveVisits produces.
+type visitStore struct {
+	file          string
+	gc            *geoCache
+	clusterMeters float64
+	noCluster     bool
+
+	mu       sync.RWMutex
+	visits   []*visit
+	features interface{}
+}
+
+func newVisitStore(file string, gc *geoCache, clusterMeters float64, noCluster bool) (*visitStore, error) {
+	s := &visitStore{file: file, gc: gc, clusterMeters: clusterMeters, noCluster: noCluster}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *visitStore) reload() error {
+	visits, err := loadVisits(s.file, s.gc)
+	if err != nil {
+		return err
+	}
+
+	var features interface{} = visits
+	if !s.noCluster {
+		features = clusterVisits(visits, s.clusterMeters)
+	}
+
+	s.mu.Lock()
+	s.visits = visits
+	s.features = features
+	s.mu.Unlock()
+
+	if err := s.gc.Save(); err != nil {
+		log.Printf("serve: saving geocode cache: %v", err)
+	}
+
+	return nil
+}
+
+func (s *visitStore) get() []*visit {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.visits
+}
+
+func (s *visitStore) getFeatures() interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.features
+}
+
+// watch uses fsnotify to reload whenever file changes on disk, so
+// editing the CSV updates the API without a restart. Editors commonly
+// replace a file rather than writing it in place, which shows up as a
+// Remove/Rename event followed by a new inode at the same path, so the
+// containing directory is watched rather than the file itself.
+func (s *visitStore) watch() error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(s.file)
+	if err := w.Add(dir); err != nil {
+		w.Close()
+		return err
+	}
+
+	go func() {
+		defer w.Close()
+		for {
+			select {
+			case event, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != filepath.Clean(s.file) {
+					continue
+				}
+				if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+					continue
+				}
+				if err := s.reload(); err != nil {
+					log.Printf("serve: reloading %s: %v", s.file, err)
+					continue
+				}
+				log.Printf("serve: reloaded %s", s.file)
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("serve: watching %s: %v", s.file, err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func serveMain(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "listen address")
+	file := fs.String("file", "travel.csv", "CSV file name")
+	apiKey := fs.String("apikey", "", "Google Maps API key (or $GOOGLE_MAPS_API_KEY)")
+	cacheFile := fs.String("cache", "travel.geocache.json", "geocode cache file name")
+	nominatim := fs.Bool("nominatim", false, "use Nominatim/OpenStreetMap instead of Google for geocoding")
+	origin := fs.String("origin", "*", "allowed CORS origin")
+	clusterMeters := fs.Float64("cluster-meters", 500, "cluster visits within this many meters of each other into one feature")
+	noCluster := fs.Bool("no-cluster", false, "emit one feature per visit instead of clustering")
+	fs.Parse(args)
+
+	var geocoder Geocoder = newGoogleGeocoder(*apiKey)
+	if *nominatim {
+		geocoder = newNominatimGeocoder()
+	}
+	gc := newGeoCache(*cacheFile, geocoder, false)
+
+	store, err := newVisitStore(*file, gc, *clusterMeters, *noCluster)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := store.watch(); err != nil {
+		log.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ping", get(handlePing))
+	mux.HandleFunc("/features.geojson", get(store.handleFeatures))
+	mux.HandleFunc("/locations", get(store.handleLocations))
+	mux.HandleFunc("/metrics", get(store.handleMetrics))
+
+	log.Printf("serve: listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, withCORS(*origin, mux)))
+}
+
+// get wraps h so it only handles GET requests; the toolchain this repo
+// builds with predates Go 1.22's method-prefixed ServeMux patterns, so
+// routes are registered on plain paths and the method is checked here.
+func get(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h(w, r)
+	}
+}
+
+func withCORS(origin string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func handlePing(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+}
+
+func (s *visitStore) handleFeatures(w http.ResponseWriter, r *http.Request) {
+	geojson := map[string]interface{}{
+		"type":     "FeatureCollection",
+		"features": s.getFeatures(),
+	}
+	writeJSON(w, geojson)
+}
+
+func (s *visitStore) handleLocations(w http.ResponseWriter, r *http.Request) {
+	visits := s.get()
+	seen := make(map[string]struct{})
+	var locs []location
+	for _, v := range visits {
+		key := visitKey(v)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		locs = append(locs, location{
+			Key:       key,
+			Latitude:  v.lat,
+			Longitude: v.lng,
+			Name:      v.address,
+		})
+	}
+	writeJSON(w, locs)
+}
+
+func (s *visitStore) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	counts := make(map[string]int)
+	var order []string
+	for _, v := range s.get() {
+		if !from.IsZero() && v.when.Before(from) {
+			continue
+		}
+		if !to.IsZero() && v.when.After(to) {
+			continue
+		}
+		key := visitKey(v)
+		if _, ok := counts[key]; !ok {
+			order = append(order, key)
+		}
+		counts[key]++
+	}
+
+	metrics := make([]metric, len(order))
+	for i, key := range order {
+		metrics[i] = metric{Key: key, Value: counts[key]}
+	}
+	writeJSON(w, metrics)
+}
+
+func parseRange(from, to string) (time.Time, time.Time, error) {
+	var f, t time.Time
+	var err error
+	if from != "" {
+		if f, err = time.Parse("2006-01-02", from); err != nil {
+			return f, t, fmt.Errorf("invalid from date %q: %w", from, err)
+		}
+	}
+	if to != "" {
+		if t, err = time.Parse("2006-01-02", to); err != nil {
+			return f, t, fmt.Errorf("invalid to date %q: %w", to, err)
+		}
+	}
+	return f, t, nil
+}
+
+func visitKey(v *visit) string {
+	return strconv.FormatFloat(v.lat, 'f', 4, 64) + "," + strconv.FormatFloat(v.lng, 'f', 4, 64)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestCSV(t *testing.T, rows string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "travel.csv")
+	if err := os.WriteFile(path, []byte(rows), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const nearbyVisitsCSV = "2024-01-01,,Home,59.3300,18.0590\n2024-01-02,,Home again,59.3301,18.0591\n"
+
+func TestHandleFeaturesMatchesFileWriterClustering(t *testing.T) {
+	file := writeTestCSV(t, nearbyVisitsCSV)
+	gc := newGeoCache(filepath.Join(t.TempDir(), "cache.json"), &fakeGeocoder{}, false)
+
+	store, err := newVisitStore(file, gc, 500, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	store.handleFeatures(w, httptest.NewRequest("GET", "/features.geojson", nil))
+
+	var got struct {
+		Features []json.RawMessage `json:"features"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("handleFeatures did not return valid JSON: %v", err)
+	}
+
+	var want struct {
+		Features []json.RawMessage `json:"features"`
+	}
+	fname := filepath.Join(t.TempDir(), "out.geojson")
+	visits, err := loadVisits(file, gc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := saveVisits(visits, fname, "geojson", 500, false, false); err != nil {
+		t.Fatal(err)
+	}
+	bs, _ := os.ReadFile(fname)
+	if err := json.Unmarshal(bs, &want); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Features) != len(want.Features) {
+		t.Fatalf("GET /features.geojson returned %d features, file writer produced %d; they must match",
+			len(got.Features), len(want.Features))
+	}
+	if len(got.Features) != 1 {
+		t.Fatalf("nearby visits were not clustered: got %d features, want 1", len(got.Features))
+	}
+}
+
+func TestHandleFeaturesNoClusterOnePerVisit(t *testing.T) {
+	file := writeTestCSV(t, nearbyVisitsCSV)
+	gc := newGeoCache(filepath.Join(t.TempDir(), "cache.json"), &fakeGeocoder{}, false)
+
+	store, err := newVisitStore(file, gc, 500, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	store.handleFeatures(w, httptest.NewRequest("GET", "/features.geojson", nil))
+
+	var got struct {
+		Features []json.RawMessage `json:"features"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Features) != 2 {
+		t.Fatalf("with -no-cluster, got %d features, want one per visit (2)", len(got.Features))
+	}
+}
+
+func TestHandleLocationsDedupesByCoordinate(t *testing.T) {
+	file := writeTestCSV(t, "2024-01-01,,Home,59.30,18.00\n2024-01-02,,Home,59.30,18.00\n2024-01-03,,Work,59.31,18.01\n")
+	gc := newGeoCache(filepath.Join(t.TempDir(), "cache.json"), &fakeGeocoder{}, false)
+
+	store, err := newVisitStore(file, gc, 500, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	store.handleLocations(w, httptest.NewRequest("GET", "/locations", nil))
+
+	var locs []location
+	if err := json.Unmarshal(w.Body.Bytes(), &locs); err != nil {
+		t.Fatal(err)
+	}
+	if len(locs) != 2 {
+		t.Fatalf("got %d locations, want 2 (exact coordinate repeats collapsed)", len(locs))
+	}
+}
+
+func TestHandleMetricsFiltersByDateRange(t *testing.T) {
+	file := writeTestCSV(t, "2024-01-01,,Home,59.30,18.00\n2024-02-01,,Home,59.30,18.00\n2024-03-01,,Work,59.31,18.01\n")
+	gc := newGeoCache(filepath.Join(t.TempDir(), "cache.json"), &fakeGeocoder{}, false)
+
+	store, err := newVisitStore(file, gc, 500, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	store.handleMetrics(w, httptest.NewRequest("GET", "/metrics?from=2024-01-15&to=2024-02-15", nil))
+
+	var metrics []metric
+	if err := json.Unmarshal(w.Body.Bytes(), &metrics); err != nil {
+		t.Fatal(err)
+	}
+	if len(metrics) != 1 {
+		t.Fatalf("got %d metrics for the filtered range, want 1 (only the Feb 1 Home visit)", len(metrics))
+	}
+	if metrics[0].Value != 1 {
+		t.Errorf("metrics[0].Value = %d, want 1", metrics[0].Value)
+	}
+}
+
+func TestGetMethodWrapperRejectsNonGET(t *testing.T) {
+	h := get(handlePing)
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("POST", "/ping", nil))
+	if w.Code != 405 {
+		t.Errorf("POST /ping = %d, want 405", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/ping", nil))
+	if w.Code != 200 {
+		t.Errorf("GET /ping = %d, want 200", w.Code)
+	}
+}